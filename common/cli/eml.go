@@ -0,0 +1,192 @@
+package cli
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// headerDecoder 用于 RFC 2047 解码 From/To/Subject 等邮件头
+var headerDecoder = new(mime.WordDecoder)
+
+// ParseEMLFile 读取 path 指向的 .eml 文件并解析为 MailConfig，
+// 附件保存到 dir 目录，dir 为空时使用系统临时目录
+func ParseEMLFile(path, dir string) (*MailConfig, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseEML(f, dir)
+}
+
+// ParseEML 解析一封 RFC 5322 邮件，递归展开 multipart 树，把正文还原到
+// Content/Type，把其余部分作为附件保存到 dir 目录，返回的 MailConfig 可
+// 直接交给 MailRun 重新发送，用于转发、退信处理等场景
+func ParseEML(r io.Reader, dir string) (*MailConfig, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		dir = os.TempDir()
+	}
+
+	cfg := &MailConfig{
+		From:    decodeHeader(msg.Header.Get("From")),
+		To:      decodeHeader(msg.Header.Get("To")),
+		Subject: decodeHeader(msg.Header.Get("Subject")),
+		Type:    "plain",
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		data, err := decodeBody(msg.Body, msg.Header.Get("Content-Transfer-Encoding"))
+		if err != nil {
+			return nil, err
+		}
+		cfg.Content = string(data)
+		return cfg, nil
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		attachments, err := walkMultipart(msg.Body, params["boundary"], cfg, dir)
+		if err != nil {
+			return nil, err
+		}
+		if len(attachments) > 0 {
+			cfg.Attachments = strings.Join(attachments, ",")
+		}
+		return cfg, nil
+	}
+
+	cfg.Type = subType(mediaType)
+	data, err := decodeBody(msg.Body, msg.Header.Get("Content-Transfer-Encoding"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Content = string(data)
+	return cfg, nil
+}
+
+// walkMultipart 递归遍历 multipart 树：text/plain 与 text/html 正文回填到 cfg
+// (同时存在时保留 html)，其余部分另存为附件，返回已保存的附件路径列表
+func walkMultipart(body io.Reader, boundary string, cfg *MailConfig, dir string) ([]string, error) {
+	var attachments []string
+	mr := multipart.NewReader(body, boundary)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		mediaType, params, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if err != nil {
+			mediaType = "text/plain"
+		}
+
+		if strings.HasPrefix(mediaType, "multipart/") {
+			nested, err := walkMultipart(part, params["boundary"], cfg, dir)
+			if err != nil {
+				return nil, err
+			}
+			attachments = append(attachments, nested...)
+			continue
+		}
+
+		disposition, _, _ := mime.ParseMediaType(part.Header.Get("Content-Disposition"))
+		filename := part.FileName()
+
+		if filename == "" && disposition != "attachment" && (mediaType == "text/plain" || mediaType == "text/html") {
+			data, err := decodeBody(part, part.Header.Get("Content-Transfer-Encoding"))
+			if err != nil {
+				return nil, err
+			}
+			if cfg.Content == "" || subType(mediaType) == "html" {
+				cfg.Content = string(data)
+				cfg.Type = subType(mediaType)
+			}
+			continue
+		}
+
+		if filename == "" {
+			filename = fmt.Sprintf("part-%d", len(attachments)+1)
+		}
+		path, err := saveAttachment(dir, filename, part, part.Header.Get("Content-Transfer-Encoding"))
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, path)
+	}
+	return attachments, nil
+}
+
+// saveAttachment 解码 r 的内容并写入 dir 目录，文件名与已有文件冲突时
+// 追加序号，避免同名附件(如转发链中重复的 image.png)互相覆盖
+func saveAttachment(dir, filename string, r io.Reader, encoding string) (string, error) {
+	data, err := decodeBody(r, encoding)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	path := uniquePath(dir, filename)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// uniquePath 返回 dir 下不与现有文件冲突的路径，冲突时在文件名主干后追加序号
+func uniquePath(dir, filename string) string {
+	base := filepath.Base(filename)
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	path := filepath.Join(dir, base)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d%s", stem, i, ext))
+	}
+}
+
+// decodeBody 按 Content-Transfer-Encoding 解码 r 的内容
+func decodeBody(r io.Reader, encoding string) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return io.ReadAll(base64.NewDecoder(base64.StdEncoding, r))
+	case "quoted-printable":
+		return io.ReadAll(quotedprintable.NewReader(r))
+	default:
+		return io.ReadAll(r)
+	}
+}
+
+// decodeHeader 对邮件头做 RFC 2047 解码，失败时返回原始值
+func decodeHeader(s string) string {
+	decoded, err := headerDecoder.DecodeHeader(s)
+	if err != nil {
+		return s
+	}
+	return decoded
+}
+
+// subType 返回 MIME 类型的子类型部分，如 "text/html" -> "html"
+func subType(mediaType string) string {
+	if i := strings.Index(mediaType, "/"); i >= 0 {
+		return mediaType[i+1:]
+	}
+	return mediaType
+}
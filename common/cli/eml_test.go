@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func buildTestEML(t *testing.T, plain, html, imgA, imgB string) string {
+	t.Helper()
+	encA := base64.StdEncoding.EncodeToString([]byte(imgA))
+	encB := base64.StdEncoding.EncodeToString([]byte(imgB))
+
+	return strings.ReplaceAll(`From: sender@example.com
+To: recipient@example.com
+Subject: =?UTF-8?B?5rWL6K+V?=
+Content-Type: multipart/mixed; boundary=mixedBoundary
+
+--mixedBoundary
+Content-Type: multipart/alternative; boundary=altBoundary
+
+--altBoundary
+Content-Type: text/plain; charset=UTF-8
+Content-Transfer-Encoding: quoted-printable
+
+`+plain+`
+--altBoundary
+Content-Type: text/html; charset=UTF-8
+Content-Transfer-Encoding: quoted-printable
+
+`+html+`
+--altBoundary--
+--mixedBoundary
+Content-Type: application/octet-stream
+Content-Disposition: attachment; filename="image.png"
+Content-Transfer-Encoding: base64
+
+`+encA+`
+--mixedBoundary
+Content-Type: application/octet-stream
+Content-Disposition: attachment; filename="image.png"
+Content-Transfer-Encoding: base64
+
+`+encB+`
+--mixedBoundary--
+`, "\n", "\r\n")
+}
+
+func TestParseEML_DecodesHTMLBodyAndDedupesAttachments(t *testing.T) {
+	dir := t.TempDir()
+	eml := buildTestEML(t, "plain body", "<b>html body</b>", "first image bytes", "second image bytes")
+
+	cfg, err := ParseEML(strings.NewReader(eml), dir)
+	if err != nil {
+		t.Fatalf("ParseEML: %v", err)
+	}
+
+	if cfg.Content != "<b>html body</b>" {
+		t.Errorf("Content = %q, want html body to win over plain", cfg.Content)
+	}
+	if cfg.Type != "html" {
+		t.Errorf("Type = %q, want %q", cfg.Type, "html")
+	}
+
+	paths := strings.Split(cfg.Attachments, ",")
+	if len(paths) != 2 {
+		t.Fatalf("Attachments = %q, want 2 paths", cfg.Attachments)
+	}
+	if paths[0] == paths[1] {
+		t.Fatalf("duplicate attachment filenames collided onto the same path: %q", paths[0])
+	}
+
+	gotA, err := os.ReadFile(paths[0])
+	if err != nil {
+		t.Fatalf("read %s: %v", paths[0], err)
+	}
+	gotB, err := os.ReadFile(paths[1])
+	if err != nil {
+		t.Fatalf("read %s: %v", paths[1], err)
+	}
+	if string(gotA) != "first image bytes" {
+		t.Errorf("first attachment = %q, want %q", gotA, "first image bytes")
+	}
+	if string(gotB) != "second image bytes" {
+		t.Errorf("second attachment = %q, want %q", gotB, "second image bytes")
+	}
+
+	for _, p := range paths {
+		if filepath.Dir(p) != dir {
+			t.Errorf("attachment %s was not saved under %s", p, dir)
+		}
+	}
+}
+
+func TestParseEML_PlainTextOnly(t *testing.T) {
+	dir := t.TempDir()
+	raw := strings.ReplaceAll(`From: sender@example.com
+To: recipient@example.com
+Subject: hello
+Content-Type: text/plain; charset=UTF-8
+Content-Transfer-Encoding: quoted-printable
+
+hello=2C world
+`, "\n", "\r\n")
+
+	cfg, err := ParseEML(strings.NewReader(raw), dir)
+	if err != nil {
+		t.Fatalf("ParseEML: %v", err)
+	}
+	if cfg.Content != "hello, world\r\n" {
+		t.Errorf("Content = %q, want %q", cfg.Content, "hello, world\r\n")
+	}
+	if cfg.Type != "plain" {
+		t.Errorf("Type = %q, want %q", cfg.Type, "plain")
+	}
+	if cfg.Attachments != "" {
+		t.Errorf("Attachments = %q, want none", cfg.Attachments)
+	}
+}
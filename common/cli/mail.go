@@ -2,15 +2,11 @@ package cli
 
 import (
 	"bytes"
-	"crypto/tls"
 	"encoding/base64"
-	"errors"
 	"fmt"
 	"io"
 	"mime"
 	"mime/multipart"
-	"mime/quotedprintable"
-	"net/smtp"
 	"net/textproto"
 	"os"
 	"path/filepath"
@@ -19,12 +15,17 @@ import (
 
 const memMaxSize = 10 << 20 // 10MB
 
-// MailRun 发送邮件
-func MailRun(mailConfig *MailConfig) error {
+// MailRun 发送邮件，sender 为 nil 时按 mailConfig 构造 SMTPSender，保持历史上的 STARTTLS 行为
+func MailRun(mailConfig *MailConfig, sender Sender) error {
 	if mailConfig.User == "" || mailConfig.Passwd == "" || mailConfig.From == "" || mailConfig.To == "" {
 		return fmt.Errorf("参数错误")
 	}
 
+	m, err := mailConfig.toMessage()
+	if err != nil {
+		return fmt.Errorf("封装邮件内容失败:%s", err)
+	}
+
 	size, err := mailConfig.Len()
 	if err != nil {
 		fmt.Printf("获取邮件大小失败:%s\n", err.Error())
@@ -42,77 +43,28 @@ func MailRun(mailConfig *MailConfig) error {
 	} else {
 		file = bytes.NewBuffer(make([]byte, 0, size))
 	}
-	err = mailConfig.Writer(file)
-	if err != nil {
+	if err = m.Writer(file); err != nil {
 		return fmt.Errorf("封装邮件内容失败:%s", err)
 	}
 
-	auth := smtp.PlainAuth("", mailConfig.User, mailConfig.Passwd, strings.Split(mailConfig.Host, ":")[0])
-	err = MailSend(mailConfig, auth, file)
-	if c, ok := file.(io.Closer); ok {
-		c.Close()
-	}
-	return err
-}
-
-// MailSend 送邮件
-func MailSend(msg *MailConfig, auth smtp.Auth, body io.Reader) error {
-	to := strings.Split(msg.To, ",")
-	if msg.From == "" || len(to) == 0 {
-		return errors.New("Must specify at least one From address and one To address")
-	}
-	client, err := smtp.Dial(msg.Host)
-	if err != nil {
-		return err
-	}
-	defer client.Close()
-
-	host := strings.Split(msg.Host, ":")[0]
-	if err = client.Hello(host); err != nil {
-		return err
-	}
-
-	if ok, _ := client.Extension("STARTTLS"); ok {
-		config := &tls.Config{ServerName: host}
-		if err = client.StartTLS(config); err != nil {
-			return err
-		}
-	}
-
-	if err = client.Auth(auth); err != nil {
-		return err
-	}
-
-	if err = client.Mail(msg.From); err != nil {
-		return err
-	}
-
-	for _, addr := range to {
-		if err = client.Rcpt(addr); err != nil {
-			return err
+	if sender == nil {
+		sender = &SMTPSender{
+			Host:     mailConfig.Host,
+			User:     mailConfig.User,
+			Passwd:   mailConfig.Passwd,
+			AuthType: mailConfig.AuthType,
+			Token:    mailConfig.Token,
 		}
 	}
 
-	w, err := client.Data()
-	if err != nil {
-		return err
-	}
-
-	if value, ok := body.(io.Seeker); ok {
+	if value, ok := file.(io.Seeker); ok {
 		value.Seek(0, 0)
 	}
-
-	_, err = io.Copy(w, body)
-	if err != nil {
-		return err
-	}
-
-	err = w.Close()
-	if err != nil {
-		return err
+	err = sender.Send(mailConfig.From, m.Recipients(), file)
+	if c, ok := file.(io.Closer); ok {
+		c.Close()
 	}
-
-	return client.Quit()
+	return err
 }
 
 // MailConfig mail config args
@@ -122,92 +74,54 @@ type MailConfig struct {
 	Subject, Content   string
 	ContentPath        string
 	Attachments        string
+	// AuthType 指定 SMTP 认证机制，默认 AuthAuto 按服务器通告自动选择
+	AuthType AuthType
+	// Token 为 AuthXOAuth2 模式下使用的 OAuth2 access token
+	Token string
 }
 
-// Headers 返回邮件头信息
-func (e *MailConfig) Headers() (textproto.MIMEHeader, error) {
-	res := make(textproto.MIMEHeader)
-	if _, ok := res["To"]; !ok && len(e.To) > 0 {
-		res.Set("To", e.To)
+// toMessage 把旧式字段转换为 Message，使 MailConfig 成为 Message 的兼容外壳
+func (e *MailConfig) toMessage() (*Message, error) {
+	m := NewMessage()
+	if e.From != "" {
+		m.SetFrom(e.From, "")
+	}
+	for _, addr := range strings.Split(e.To, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			m.AddTo(addr, "")
+		}
+	}
+	if e.Subject != "" {
+		m.SetSubject(e.Subject)
 	}
 
-	if _, ok := res["Subject"]; !ok && e.Subject != "" {
-		res.Set("Subject", e.Subject)
+	content := e.Content
+	if content == "" && e.ContentPath != "" {
+		data, err := os.ReadFile(e.ContentPath)
+		if err != nil {
+			return nil, err
+		}
+		content = string(data)
+	}
+	if content != "" {
+		m.SetBody(e.Type, content)
 	}
 
-	if _, ok := res["From"]; !ok {
-		res.Set("From", e.From)
+	if e.Attachments != "" {
+		for _, path := range strings.Split(e.Attachments, ",") {
+			m.Attach(path)
+		}
 	}
-	return res, nil
+	return m, nil
 }
 
-// Writer 写入附件
+// Writer 把 MailConfig 转换为 Message 并写出完整的 MIME 邮件
 func (e *MailConfig) Writer(datawriter io.Writer) error {
-	headers, err := e.Headers()
+	m, err := e.toMessage()
 	if err != nil {
 		return err
 	}
-	w := multipart.NewWriter(datawriter)
-
-	headers.Set("Content-Type", "multipart/mixed;\r\n boundary="+w.Boundary())
-	headerToBytes(datawriter, headers)
-	io.WriteString(datawriter, "\r\n")
-
-	fmt.Fprintf(datawriter, "--%s\r\n", w.Boundary())
-	header := textproto.MIMEHeader{}
-	if e.Content != "" || e.ContentPath != "" {
-		subWriter := multipart.NewWriter(datawriter)
-		header.Set("Content-Type", fmt.Sprintf("multipart/alternative;\r\n boundary=%s\r\n", subWriter.Boundary()))
-		headerToBytes(datawriter, header)
-		if e.Content != "" {
-			header.Set("Content-Type", fmt.Sprintf("text/%s; charset=UTF-8", e.Type))
-			header.Set("Content-Transfer-Encoding", "quoted-printable")
-			if _, err := subWriter.CreatePart(header); err != nil {
-				return err
-			}
-			qp := quotedprintable.NewWriter(datawriter)
-			if _, err := qp.Write([]byte(e.Content)); err != nil {
-				return err
-			}
-			if err := qp.Close(); err != nil {
-				return err
-			}
-		} else {
-			header.Set("Content-Type", fmt.Sprintf("text/%s; charset=UTF-8", e.Type))
-			header.Set("Content-Transfer-Encoding", "quoted-printable")
-			if _, err := subWriter.CreatePart(header); err != nil {
-				return err
-			}
-			qp := quotedprintable.NewWriter(datawriter)
-			File, err := os.Open(e.ContentPath)
-			if err != nil {
-				return err
-			}
-			defer File.Close()
-
-			_, err = io.Copy(qp, File)
-			if err != nil {
-				return err
-			}
-			if err := qp.Close(); err != nil {
-				return err
-			}
-		}
-		if err := subWriter.Close(); err != nil {
-			return err
-		}
-	}
-	if e.Attachments != "" {
-		list := strings.Split(e.Attachments, ",")
-		for _, path := range list {
-			err = attach(w, path)
-			if err != nil {
-				w.Close()
-				return err
-			}
-		}
-	}
-	return nil
+	return m.Writer(datawriter)
 }
 
 // Len 获取邮件大小
@@ -253,29 +167,58 @@ func headerToBytes(w io.Writer, header textproto.MIMEHeader) {
 }
 
 // attach 封装附件
-func attach(w *multipart.Writer, filename string) (err error) {
+func attach(w *multipart.Writer, filename string) error {
+	header := make(textproto.MIMEHeader)
 	typ := mime.TypeByExtension(filepath.Ext(filename))
-	var Header = make(textproto.MIMEHeader)
-	if typ != "" {
-		Header.Set("Content-Type", typ)
-	} else {
-		Header.Set("Content-Type", "application/octet-stream")
+	if typ == "" {
+		typ = "application/octet-stream"
 	}
+	header.Set("Content-Type", typ)
 	basename := filepath.Base(filename)
-	Header.Set("Content-Disposition", fmt.Sprintf("attachment;\r\n filename=\"%s\"", basename))
-	Header.Set("Content-ID", fmt.Sprintf("<%s>", basename))
-	Header.Set("Content-Transfer-Encoding", "base64")
+	header.Set("Content-ID", fmt.Sprintf("<%s>", basename))
+
 	File, err := os.Open(filename)
 	if err != nil {
 		return err
 	}
 	defer File.Close()
 
-	mw, err := w.CreatePart(Header)
+	return writePart(w, "attachment", basename, header, File)
+}
+
+// embed 封装内嵌资源，e.r 非空时优先使用它，否则从 e.path 打开文件
+func embed(w *multipart.Writer, e *embedFile) error {
+	header := make(textproto.MIMEHeader)
+	typ := mime.TypeByExtension(filepath.Ext(e.name))
+	if typ == "" {
+		typ = "application/octet-stream"
+	}
+	header.Set("Content-Type", typ)
+	header.Set("Content-ID", fmt.Sprintf("<%s>", e.name))
+
+	r := e.r
+	if r == nil {
+		File, err := os.Open(e.path)
+		if err != nil {
+			return err
+		}
+		defer File.Close()
+		r = File
+	}
+
+	return writePart(w, "inline", e.name, header, r)
+}
+
+// writePart 以 base64 编码把 r 的内容写入一个 MIME 子部分，供附件与内嵌资源共用
+func writePart(w *multipart.Writer, disposition, filename string, header textproto.MIMEHeader, r io.Reader) error {
+	header.Set("Content-Disposition", fmt.Sprintf("%s;\r\n filename=\"%s\"", disposition, filename))
+	header.Set("Content-Transfer-Encoding", "base64")
+
+	mw, err := w.CreatePart(header)
 	if err != nil {
 		return err
 	}
-	return base64Wrap(mw, File)
+	return base64Wrap(mw, r)
 }
 
 func base64Wrap(w io.Writer, r io.Reader) error {
@@ -287,20 +230,22 @@ func base64Wrap(w io.Writer, r io.Reader) error {
 	var b = make([]byte, maxRaw)
 	for {
 		n, err := r.Read(b)
+		if n > 0 {
+			if n == maxRaw {
+				base64.StdEncoding.Encode(buffer, b[:n])
+				w.Write(buffer)
+			} else {
+				out := buffer[:base64.StdEncoding.EncodedLen(n)]
+				base64.StdEncoding.Encode(out, b[:n])
+				out = append(out, "\r\n"...)
+				w.Write(out)
+			}
+		}
 		if err != nil {
 			if err == io.EOF {
 				return nil
 			}
 			return err
 		}
-		if n == maxRaw {
-			base64.StdEncoding.Encode(buffer, b[:n])
-			w.Write(buffer)
-		} else {
-			out := buffer[:base64.StdEncoding.EncodedLen(len(b))]
-			base64.StdEncoding.Encode(out, b)
-			out = append(out, "\r\n"...)
-			w.Write(out)
-		}
 	}
 }
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"fmt"
+	"net/smtp"
+	"strings"
+)
+
+// AuthType 标识邮件认证使用的机制
+type AuthType int
+
+const (
+	// AuthAuto 根据服务器通告的 AUTH 机制自动选择，优先级 CRAM-MD5 > XOAUTH2 > LOGIN > PLAIN
+	AuthAuto AuthType = iota
+	AuthPlain
+	AuthLogin
+	AuthCRAMMD5
+	AuthXOAuth2
+)
+
+// loginAuth 实现 AUTH LOGIN 机制，响应服务器的 Username:/Password: 质询
+type loginAuth struct {
+	username, password string
+}
+
+// LoginAuth 返回一个 AUTH LOGIN 认证器
+func LoginAuth(username, password string) smtp.Auth {
+	return &loginAuth{username, password}
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch string(fromServer) {
+	case "Username:":
+		return []byte(a.username), nil
+	case "Password:":
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("未知的 LOGIN 质询:%s", fromServer)
+	}
+}
+
+// xoauth2Auth 实现 AUTH XOAUTH2 机制，使用 OAuth2 access token 代替密码
+type xoauth2Auth struct {
+	username, token string
+}
+
+// XOAuth2Auth 返回一个 AUTH XOAUTH2 认证器
+func XOAuth2Auth(username, token string) smtp.Auth {
+	return &xoauth2Auth{username, token}
+}
+
+func (a *xoauth2Auth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	resp := fmt.Sprintf("user=%s\x01auth=Bearer %s\x01\x01", a.username, a.token)
+	return "XOAUTH2", []byte(resp), nil
+}
+
+func (a *xoauth2Auth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if more {
+		// 服务器返回了错误质询(JSON)，回复空响应以结束认证
+		return []byte{}, nil
+	}
+	return nil, nil
+}
+
+// chooseAuth 依据 authType 以及服务器 AUTH 扩展通告的机制列表选择认证方式
+func chooseAuth(client *smtp.Client, host, user, passwd, token string, authType AuthType) (smtp.Auth, error) {
+	advertised := map[string]bool{}
+	if ok, mechanisms := client.Extension("AUTH"); ok {
+		for _, m := range strings.Fields(mechanisms) {
+			advertised[strings.ToUpper(m)] = true
+		}
+	}
+
+	switch authType {
+	case AuthLogin:
+		return LoginAuth(user, passwd), nil
+	case AuthCRAMMD5:
+		return smtp.CRAMMD5Auth(user, passwd), nil
+	case AuthXOAuth2:
+		return XOAuth2Auth(user, token), nil
+	case AuthPlain:
+		return smtp.PlainAuth("", user, passwd, host), nil
+	default: // AuthAuto
+		switch {
+		case advertised["CRAM-MD5"]:
+			return smtp.CRAMMD5Auth(user, passwd), nil
+		case advertised["XOAUTH2"] && token != "":
+			return XOAuth2Auth(user, token), nil
+		case advertised["LOGIN"]:
+			return LoginAuth(user, passwd), nil
+		default:
+			return smtp.PlainAuth("", user, passwd, host), nil
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"testing"
+)
+
+// fakeEHLOServer accepts a single connection and replies to EHLO with the
+// given AUTH mechanism list (or a bare 250 if empty), just enough for
+// smtp.Dial+Hello to populate client.Extension("AUTH").
+func fakeEHLOServer(t *testing.T, authMechanisms string) string {
+	return fakeSMTPServer(t, func(conn net.Conn) {
+		r := bufio.NewReader(conn)
+		fmt.Fprintf(conn, "220 fake.test ESMTP\r\n")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			upper := strings.ToUpper(strings.TrimRight(line, "\r\n"))
+			switch {
+			case strings.HasPrefix(upper, "EHLO"), strings.HasPrefix(upper, "HELO"):
+				if authMechanisms != "" {
+					fmt.Fprintf(conn, "250-fake.test\r\n250 AUTH %s\r\n", authMechanisms)
+				} else {
+					fmt.Fprintf(conn, "250 fake.test\r\n")
+				}
+			case upper == "QUIT":
+				fmt.Fprintf(conn, "221 bye\r\n")
+				return
+			default:
+				fmt.Fprintf(conn, "250 ok\r\n")
+			}
+		}
+	})
+}
+
+func dialForAuth(t *testing.T, authMechanisms string) *smtp.Client {
+	t.Helper()
+	addr := fakeEHLOServer(t, authMechanisms)
+	client, err := smtp.Dial(addr)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	if err := client.Hello("localhost"); err != nil {
+		t.Fatalf("Hello: %v", err)
+	}
+	return client
+}
+
+func TestChooseAuth_AutoPrefersCRAMMD5(t *testing.T) {
+	client := dialForAuth(t, "LOGIN PLAIN CRAM-MD5")
+	auth, err := chooseAuth(client, "localhost", "u", "p", "", AuthAuto)
+	if err != nil {
+		t.Fatalf("chooseAuth: %v", err)
+	}
+	if got := fmt.Sprintf("%T", auth); got != "*smtp.cramMD5Auth" {
+		t.Errorf("auth type = %s, want CRAM-MD5", got)
+	}
+}
+
+func TestChooseAuth_AutoPrefersXOAuth2OverLoginWhenTokenSet(t *testing.T) {
+	client := dialForAuth(t, "LOGIN XOAUTH2")
+	auth, err := chooseAuth(client, "localhost", "u", "p", "tok", AuthAuto)
+	if err != nil {
+		t.Fatalf("chooseAuth: %v", err)
+	}
+	if _, ok := auth.(*xoauth2Auth); !ok {
+		t.Errorf("auth type = %T, want *xoauth2Auth", auth)
+	}
+}
+
+func TestChooseAuth_AutoSkipsXOAuth2WithoutToken(t *testing.T) {
+	client := dialForAuth(t, "LOGIN XOAUTH2")
+	auth, err := chooseAuth(client, "localhost", "u", "p", "", AuthAuto)
+	if err != nil {
+		t.Fatalf("chooseAuth: %v", err)
+	}
+	if _, ok := auth.(*loginAuth); !ok {
+		t.Errorf("auth type = %T, want *loginAuth (no token, fall back to LOGIN)", auth)
+	}
+}
+
+func TestChooseAuth_AutoFallsBackToPlain(t *testing.T) {
+	client := dialForAuth(t, "PLAIN")
+	auth, err := chooseAuth(client, "localhost", "u", "p", "", AuthAuto)
+	if err != nil {
+		t.Fatalf("chooseAuth: %v", err)
+	}
+	if got := fmt.Sprintf("%T", auth); got != "*smtp.plainAuth" {
+		t.Errorf("auth type = %s, want PLAIN", got)
+	}
+}
+
+func TestChooseAuth_ExplicitTypeIgnoresAdvertised(t *testing.T) {
+	client := dialForAuth(t, "CRAM-MD5")
+	auth, err := chooseAuth(client, "localhost", "u", "p", "", AuthLogin)
+	if err != nil {
+		t.Fatalf("chooseAuth: %v", err)
+	}
+	if _, ok := auth.(*loginAuth); !ok {
+		t.Errorf("auth type = %T, want *loginAuth (explicit AuthLogin overrides advertised mechanisms)", auth)
+	}
+}
@@ -0,0 +1,276 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"path/filepath"
+	"strings"
+)
+
+// bodyPart 是 multipart/alternative 中的一个正文分支，contentType 形如 "plain"、"html"
+type bodyPart struct {
+	contentType string
+	body        string
+}
+
+// attachment 描述一个附件，文件在 Writer 写出邮件时才会被打开
+type attachment struct {
+	name string
+	path string
+}
+
+// embedFile 描述一个内嵌资源，HTML 正文通过 cid:name 引用，path 为空时使用 r
+type embedFile struct {
+	name string
+	path string
+	r    io.Reader
+}
+
+// Message 是邮件的高阶构建器，支持多收件人、抄送/密送、回复地址
+// 以及纯文本/HTML 二选一或二者皆有的正文
+type Message struct {
+	header      textproto.MIMEHeader
+	from        string
+	to, cc, bcc []string
+	parts       []bodyPart
+	attachments []*attachment
+	embeds      []*embedFile
+}
+
+// NewMessage 创建一个空消息
+func NewMessage() *Message {
+	return &Message{header: make(textproto.MIMEHeader)}
+}
+
+// SetFrom 设置发件人，同时写入 From 头和信封发件地址
+func (m *Message) SetFrom(addr, name string) {
+	m.from = addr
+	m.SetAddressHeader("From", addr, name)
+}
+
+// From 返回信封发件地址
+func (m *Message) From() string {
+	return m.from
+}
+
+// SetAddressHeader 设置一个地址类邮件头，name 非空时按 RFC 2047 编码显示名
+func (m *Message) SetAddressHeader(field, addr, name string) {
+	m.header.Set(field, formatAddress(addr, name))
+}
+
+// addAddressHeader 向已有的地址类邮件头追加一个地址
+func (m *Message) addAddressHeader(field, addr, name string) {
+	formatted := formatAddress(addr, name)
+	if existing := m.header.Get(field); existing != "" {
+		formatted = existing + ", " + formatted
+	}
+	m.header.Set(field, formatted)
+}
+
+// AddTo 追加一个收件人，同时写入 To 头和信封收件人列表
+func (m *Message) AddTo(addr, name string) {
+	m.to = append(m.to, addr)
+	m.addAddressHeader("To", addr, name)
+}
+
+// AddCc 追加一个抄送人，同时写入 Cc 头和信封收件人列表
+func (m *Message) AddCc(addr, name string) {
+	m.cc = append(m.cc, addr)
+	m.addAddressHeader("Cc", addr, name)
+}
+
+// AddBcc 追加一个密送人，仅加入信封收件人列表，不会出现在邮件头中
+func (m *Message) AddBcc(addr, name string) {
+	m.bcc = append(m.bcc, addr)
+}
+
+// AddReplyTo 追加一个回复地址
+func (m *Message) AddReplyTo(addr, name string) {
+	m.addAddressHeader("Reply-To", addr, name)
+}
+
+// SetSubject 设置邮件主题
+func (m *Message) SetSubject(subject string) {
+	m.header.Set("Subject", subject)
+}
+
+// Recipients 返回 To+Cc+Bcc 去重后的并集，用于 SMTP 信封收件人
+func (m *Message) Recipients() []string {
+	seen := make(map[string]bool, len(m.to)+len(m.cc)+len(m.bcc))
+	res := make([]string, 0, len(m.to)+len(m.cc)+len(m.bcc))
+	for _, group := range [][]string{m.to, m.cc, m.bcc} {
+		for _, addr := range group {
+			if !seen[addr] {
+				seen[addr] = true
+				res = append(res, addr)
+			}
+		}
+	}
+	return res
+}
+
+// SetBody 设置(替换)正文，contentType 形如 "plain" 或 "html"
+func (m *Message) SetBody(contentType, body string) {
+	m.parts = []bodyPart{{contentType: contentType, body: body}}
+}
+
+// AddAlternative 追加一个可选正文分支，与 SetBody 一起构成 multipart/alternative
+func (m *Message) AddAlternative(contentType, body string) {
+	m.parts = append(m.parts, bodyPart{contentType: contentType, body: body})
+}
+
+// Attach 添加一个文件附件
+func (m *Message) Attach(path string) {
+	m.attachments = append(m.attachments, &attachment{name: filepath.Base(path), path: path})
+}
+
+// Embed 添加一个内嵌图片，HTML 正文中通过 `<img src="cid:`+filepath.Base(path)+`">` 引用
+func (m *Message) Embed(path string) {
+	m.embeds = append(m.embeds, &embedFile{name: filepath.Base(path), path: path})
+}
+
+// EmbedReader 添加一个内嵌图片，内容从 r 读取，HTML 正文中通过 `cid:name` 引用
+func (m *Message) EmbedReader(name string, r io.Reader) {
+	m.embeds = append(m.embeds, &embedFile{name: name, r: r})
+}
+
+// Writer 将消息编码为 multipart/mixed 格式写入 w；存在内嵌图片时
+// MIME 结构为 mixed(related(alternative(plain, html), embeds), attachments)
+func (m *Message) Writer(w io.Writer) error {
+	mw := multipart.NewWriter(w)
+	header := make(textproto.MIMEHeader, len(m.header)+1)
+	for k, v := range m.header {
+		header[k] = v
+	}
+	header.Set("Content-Type", "multipart/mixed;\r\n boundary="+mw.Boundary())
+	headerToBytes(w, header)
+	io.WriteString(w, "\r\n")
+
+	var err error
+	if len(m.embeds) > 0 {
+		err = m.writeRelated(mw)
+	} else {
+		err = m.writeAlternative(mw)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, a := range m.attachments {
+		if err := attach(mw, a.path); err != nil {
+			mw.Close()
+			return err
+		}
+	}
+
+	return mw.Close()
+}
+
+// writeRelated 把 alternative 正文与内嵌图片一起包装为 multipart/related 子部分写入 mw
+func (m *Message) writeRelated(mw *multipart.Writer) error {
+	boundary := multipart.NewWriter(io.Discard).Boundary()
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", "multipart/related;\r\n boundary="+boundary)
+	partWriter, err := mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+
+	related := multipart.NewWriter(partWriter)
+	if err := related.SetBoundary(boundary); err != nil {
+		return err
+	}
+
+	if err := m.writeAlternative(related); err != nil {
+		return err
+	}
+
+	for _, e := range m.embeds {
+		if err := embed(related, e); err != nil {
+			related.Close()
+			return err
+		}
+	}
+
+	return related.Close()
+}
+
+// writeAlternative 把 parts 编码为一个 multipart/alternative 子部分写入 mw
+func (m *Message) writeAlternative(mw *multipart.Writer) error {
+	if len(m.parts) == 0 {
+		return nil
+	}
+
+	boundary := multipart.NewWriter(io.Discard).Boundary()
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", "multipart/alternative;\r\n boundary="+boundary)
+	partWriter, err := mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+
+	sub := multipart.NewWriter(partWriter)
+	if err := sub.SetBoundary(boundary); err != nil {
+		return err
+	}
+
+	for _, p := range m.parts {
+		partHeader := textproto.MIMEHeader{}
+		partHeader.Set("Content-Type", fmt.Sprintf("text/%s; charset=UTF-8", p.contentType))
+		partHeader.Set("Content-Transfer-Encoding", "quoted-printable")
+		bw, err := sub.CreatePart(partHeader)
+		if err != nil {
+			return err
+		}
+		qp := quotedprintable.NewWriter(bw)
+		if _, err := qp.Write([]byte(p.body)); err != nil {
+			return err
+		}
+		if err := qp.Close(); err != nil {
+			return err
+		}
+	}
+
+	return sub.Close()
+}
+
+// formatAddress 按 "Name <addr>" 格式组装地址，name 为空时只返回地址本身；
+// name 含 RFC 2047 需编码的字符时先编码，含 RFC 5322 特殊字符时加引号转义，
+// 避免这类字符与逗号分隔的地址列表混淆
+func formatAddress(addr, name string) string {
+	if name == "" {
+		return addr
+	}
+	encoded := mime.QEncoding.Encode("UTF-8", name)
+	if encoded == name && needsQuoting(name) {
+		encoded = quoteAddressName(name)
+	}
+	return fmt.Sprintf("%s <%s>", encoded, addr)
+}
+
+// rfc5322Specials 是 RFC 5322 atom 中不允许出现、必须加引号转义的字符
+const rfc5322Specials = "()<>[]:;@\\,.\""
+
+// needsQuoting 判断 name 是否含 RFC 5322 特殊字符，需要加引号
+func needsQuoting(name string) bool {
+	return strings.ContainsAny(name, rfc5322Specials)
+}
+
+// quoteAddressName 把 name 包装为 RFC 5322 quoted-string，转义内部的 " 和 \
+func quoteAddressName(name string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c == '"' || c == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	b.WriteByte('"')
+	return b.String()
+}
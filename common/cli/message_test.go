@@ -0,0 +1,243 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeTempFile 在 dir 下创建一个内容为 content 的文件，返回其路径
+func writeTempFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+// decodedPart 是一个已经按 Content-Transfer-Encoding 解码并读入内存的叶子部分，
+// 因为 multipart.Part 在 NextPart 被再次调用后即失效，必须在遍历时就地读完
+type decodedPart struct {
+	header textproto.MIMEHeader
+	body   string
+}
+
+// decodePartBody 按 Content-Transfer-Encoding 解码 part 的内容
+func decodePartBody(t *testing.T, part *multipart.Part) string {
+	t.Helper()
+	var r io.Reader = part
+	switch part.Header.Get("Content-Transfer-Encoding") {
+	case "quoted-printable":
+		r = quotedprintable.NewReader(part)
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, part)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("read part: %v", err)
+	}
+	return string(data)
+}
+
+// collectParts 递归展开 r 中的 multipart 树，返回按 Content-Type 索引的叶子部分
+func collectParts(t *testing.T, r io.Reader, boundary string) map[string][]decodedPart {
+	t.Helper()
+	out := make(map[string][]decodedPart)
+	mr := multipart.NewReader(r, boundary)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("NextPart: %v", err)
+		}
+		mediaType, params, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if err != nil {
+			t.Fatalf("ParseMediaType: %v", err)
+		}
+		if strings.HasPrefix(mediaType, "multipart/") {
+			var buf bytes.Buffer
+			if _, err := io.Copy(&buf, part); err != nil {
+				t.Fatalf("copy nested part: %v", err)
+			}
+			nested := collectParts(t, &buf, params["boundary"])
+			for k, v := range nested {
+				out[k] = append(out[k], v...)
+			}
+			continue
+		}
+		out[mediaType] = append(out[mediaType], decodedPart{header: part.Header, body: decodePartBody(t, part)})
+	}
+	return out
+}
+
+// writeAndParse 写出 m 并解析出外层 multipart/mixed 信封的 Content-Type 与 body
+func writeAndParse(t *testing.T, m *Message) (textproto.MIMEHeader, map[string][]decodedPart) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := m.Writer(&buf); err != nil {
+		t.Fatalf("Writer: %v", err)
+	}
+
+	tp := textproto.NewReader(bufio.NewReader(&buf))
+	header, err := tp.ReadMIMEHeader()
+	if err != nil {
+		t.Fatalf("ReadMIMEHeader: %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("ParseMediaType: %v", err)
+	}
+	if mediaType != "multipart/mixed" {
+		t.Fatalf("top-level Content-Type = %s, want multipart/mixed", mediaType)
+	}
+	return header, collectParts(t, tp.R, params["boundary"])
+}
+
+func TestMessageWriter_PlainOnly(t *testing.T) {
+	m := NewMessage()
+	m.SetFrom("a@example.com", "")
+	m.AddTo("b@example.com", "")
+	m.SetBody("plain", "hello world")
+
+	_, parts := writeAndParse(t, m)
+	plain, ok := parts["text/plain"]
+	if !ok || len(plain) != 1 {
+		t.Fatalf("text/plain parts = %d, want 1", len(parts["text/plain"]))
+	}
+	if got := plain[0].body; got != "hello world" {
+		t.Errorf("plain body = %q, want %q", got, "hello world")
+	}
+	if len(parts["text/html"]) != 0 {
+		t.Errorf("unexpected text/html parts")
+	}
+}
+
+func TestMessageWriter_AlternativeWithAttachment(t *testing.T) {
+	dir := t.TempDir()
+	// 100 bytes: not a multiple of base64Wrap's 57-byte line size, so the
+	// final short read is exercised too
+	content := strings.Repeat("a", 100)
+	path := writeTempFile(t, dir, "note.dat", content)
+
+	m := NewMessage()
+	m.SetFrom("a@example.com", "")
+	m.AddTo("b@example.com", "")
+	m.SetBody("plain", "plain body")
+	m.AddAlternative("html", "<b>html body</b>")
+	m.Attach(path)
+
+	_, parts := writeAndParse(t, m)
+	if got := parts["text/plain"][0].body; got != "plain body" {
+		t.Errorf("plain body = %q, want %q", got, "plain body")
+	}
+	if got := parts["text/html"][0].body; got != "<b>html body</b>" {
+		t.Errorf("html body = %q, want %q", got, "<b>html body</b>")
+	}
+	attachments, ok := parts["application/octet-stream"]
+	if !ok || len(attachments) != 1 {
+		t.Fatalf("attachment parts = %d, want 1", len(parts["application/octet-stream"]))
+	}
+	if disp := attachments[0].header.Get("Content-Disposition"); !strings.Contains(disp, `filename="note.dat"`) {
+		t.Errorf("Content-Disposition = %q, want filename note.dat", disp)
+	}
+	if got := attachments[0].body; got != content {
+		t.Errorf("attachment content mismatch")
+	}
+}
+
+func TestMessageWriter_RelatedWithEmbed(t *testing.T) {
+	m := NewMessage()
+	m.SetFrom("a@example.com", "")
+	m.AddTo("b@example.com", "")
+	m.SetBody("html", `<img src="cid:logo.xyz">`)
+	// not a multiple of base64Wrap's 57-byte line size, see note in the attachment test above
+	embedContent := strings.Repeat("b", 100)
+	m.EmbedReader("logo.xyz", strings.NewReader(embedContent))
+
+	_, parts := writeAndParse(t, m)
+	if got := parts["text/html"][0].body; got != `<img src="cid:logo.xyz">` {
+		t.Errorf("html body = %q", got)
+	}
+	embeds, ok := parts["application/octet-stream"]
+	if !ok || len(embeds) != 1 {
+		t.Fatalf("embed parts = %d, want 1", len(parts["application/octet-stream"]))
+	}
+	if cid := embeds[0].header.Get("Content-ID"); cid != "<logo.xyz>" {
+		t.Errorf("Content-ID = %q, want <logo.xyz>", cid)
+	}
+	if got := embeds[0].body; got != embedContent {
+		t.Errorf("embed content mismatch")
+	}
+	if disp := embeds[0].header.Get("Content-Disposition"); !strings.HasPrefix(disp, "inline;") {
+		t.Errorf("Content-Disposition = %q, want inline", disp)
+	}
+}
+
+func TestAddTo_QuotesNameWithComma(t *testing.T) {
+	m := NewMessage()
+	m.AddTo("b@example.com", "Smith, John")
+	m.AddTo("c@example.com", "Jane Doe")
+
+	addrs, err := mail.ParseAddressList(m.header.Get("To"))
+	if err != nil {
+		t.Fatalf("ParseAddressList: %v", err)
+	}
+	if len(addrs) != 2 {
+		t.Fatalf("parsed %d addresses, want 2: %+v", len(addrs), addrs)
+	}
+	if addrs[0].Name != "Smith, John" || addrs[0].Address != "b@example.com" {
+		t.Errorf("addrs[0] = %+v, want Name=%q Address=%q", addrs[0], "Smith, John", "b@example.com")
+	}
+	if addrs[1].Name != "Jane Doe" || addrs[1].Address != "c@example.com" {
+		t.Errorf("addrs[1] = %+v, want Name=%q Address=%q", addrs[1], "Jane Doe", "c@example.com")
+	}
+}
+
+func TestRecipients_DedupesAcrossToCcBccAndOmitsBccHeader(t *testing.T) {
+	m := NewMessage()
+	m.SetFrom("a@example.com", "")
+	m.AddTo("b@example.com", "")
+	m.AddCc("c@example.com", "")
+	m.AddBcc("b@example.com", "")
+	m.AddBcc("d@example.com", "")
+
+	want := []string{"b@example.com", "c@example.com", "d@example.com"}
+	if got := m.Recipients(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Recipients() = %v, want %v", got, want)
+	}
+
+	header, _ := writeAndParse(t, m)
+	if _, ok := header["Bcc"]; ok {
+		t.Errorf("Bcc header = %v, want absent", header["Bcc"])
+	}
+}
+
+func TestFormatAddress_QuotesSpecialsButNotPlainNames(t *testing.T) {
+	cases := []struct {
+		name string
+		want string
+	}{
+		{"Jane Doe", `Jane Doe <a@example.com>`},
+		{`Smith, John`, `"Smith, John" <a@example.com>`},
+		{`Say "hi"`, `"Say \"hi\"" <a@example.com>`},
+	}
+	for _, c := range cases {
+		if got := formatAddress("a@example.com", c.name); got != c.want {
+			t.Errorf("formatAddress(%q) = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package cli
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Pool 维护到同一 SMTP 服务器的一个或多个已认证连接，在多封邮件之间通过
+// RSET 复用连接，并在服务器通告 PIPELINING 时批量发出 MAIL/RCPT/DATA 命令，
+// 适合单次发送大批量邮件的场景
+type Pool struct {
+	Host, User, Passwd string
+	AuthType           AuthType
+	Token              string
+	// Size 为连接池中保留的空闲连接数上限，<=0 时视为 1
+	Size int
+
+	mu    sync.Mutex
+	conns []*poolConn
+}
+
+// poolConn 是池中的一条已认证连接
+type poolConn struct {
+	client     *smtp.Client
+	pipelining bool
+}
+
+// dial 建立一条新连接并完成 HELO/STARTTLS/AUTH
+func (p *Pool) dial() (*poolConn, error) {
+	client, err := smtp.Dial(p.Host)
+	if err != nil {
+		return nil, err
+	}
+	host := strings.Split(p.Host, ":")[0]
+	if err = client.Hello(host); err != nil {
+		client.Close()
+		return nil, err
+	}
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err = client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+
+	auth, err := chooseAuth(client, host, p.User, p.Passwd, p.Token, p.AuthType)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	if err = client.Auth(auth); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	pipelining, _ := client.Extension("PIPELINING")
+	return &poolConn{client: client, pipelining: pipelining}, nil
+}
+
+// acquire 从空闲连接中取出一条，没有空闲连接时新建
+func (p *Pool) acquire() (*poolConn, error) {
+	p.mu.Lock()
+	if n := len(p.conns); n > 0 {
+		c := p.conns[n-1]
+		p.conns = p.conns[:n-1]
+		p.mu.Unlock()
+		return c, nil
+	}
+	p.mu.Unlock()
+	return p.dial()
+}
+
+// release 把连接放回空闲池，超过 Size 上限时直接关闭
+func (p *Pool) release(c *poolConn) {
+	size := p.Size
+	if size <= 0 {
+		size = 1
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.conns) >= size {
+		c.client.Close()
+		return
+	}
+	p.conns = append(p.conns, c)
+}
+
+// drain 关闭并清空池中所有空闲连接，用于 421 Service closing 之后重新建连
+func (p *Pool) drain() {
+	p.mu.Lock()
+	conns := p.conns
+	p.conns = nil
+	p.mu.Unlock()
+	for _, c := range conns {
+		c.client.Close()
+	}
+}
+
+// Close 关闭连接池中所有空闲连接
+func (p *Pool) Close() {
+	p.drain()
+}
+
+// Send 编码并发送一封消息；遇到 4xx 临时错误时按指数退避重连重试，
+// 遇到 421(服务关闭) 时清空连接池后重新建立连接
+func (p *Pool) Send(msg *Message) error {
+	from := msg.From()
+	to := msg.Recipients()
+	if from == "" || len(to) == 0 {
+		return errors.New("Must specify at least one From address and one To address")
+	}
+
+	var buf bytes.Buffer
+	if err := msg.Writer(&buf); err != nil {
+		return err
+	}
+
+	const maxAttempts = 3
+	backoff := 500 * time.Millisecond
+	var lastErr error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		conn, err := p.acquire()
+		if err != nil {
+			return err
+		}
+
+		if err = conn.deliver(from, to, bytes.NewReader(buf.Bytes())); err == nil {
+			if err = conn.client.Reset(); err != nil {
+				conn.client.Close()
+				return nil
+			}
+			p.release(conn)
+			return nil
+		}
+
+		conn.client.Close()
+		lastErr = err
+
+		code := smtpErrorCode(err)
+		if code != 421 && !(code >= 400 && code < 500) {
+			return err
+		}
+		if code == 421 {
+			p.drain()
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	return lastErr
+}
+
+// SendBatch 依次发送多封消息并复用连接池，返回每封消息对应的发送错误(成功为 nil)
+func (p *Pool) SendBatch(msgs []*Message) []error {
+	errs := make([]error, len(msgs))
+	for i, msg := range msgs {
+		errs[i] = p.Send(msg)
+	}
+	return errs
+}
+
+// deliver 在已认证的连接上投递一封邮件；服务器通告 PIPELINING 时
+// 把 MAIL/RCPT/DATA 连续发出而不等待中间响应，随后按顺序读取全部响应
+func (c *poolConn) deliver(from string, to []string, msg io.Reader) error {
+	if !c.pipelining {
+		// 不调用 deliver()：它会 QUIT，而这里需要保持连接以便 RSET 复用
+		if err := c.client.Mail(from); err != nil {
+			return err
+		}
+		for _, addr := range to {
+			if err := c.client.Rcpt(addr); err != nil {
+				return err
+			}
+		}
+		w, err := c.client.Data()
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(w, msg); err != nil {
+			return err
+		}
+		return w.Close()
+	}
+
+	text := c.client.Text
+	cmds := make([]string, 0, len(to)+2)
+	cmds = append(cmds, fmt.Sprintf("MAIL FROM:<%s>", from))
+	for _, addr := range to {
+		cmds = append(cmds, fmt.Sprintf("RCPT TO:<%s>", addr))
+	}
+	cmds = append(cmds, "DATA")
+
+	ids := make([]uint, len(cmds))
+	for i, cmd := range cmds {
+		id, err := text.Cmd("%s", cmd)
+		if err != nil {
+			return err
+		}
+		ids[i] = id
+	}
+
+	for i, id := range ids {
+		expect := 250
+		if i == len(ids)-1 {
+			expect = 354
+		}
+		text.StartResponse(id)
+		_, _, err := text.ReadResponse(expect)
+		text.EndResponse(id)
+		if err != nil {
+			return err
+		}
+	}
+
+	dw := text.DotWriter()
+	if _, err := io.Copy(dw, msg); err != nil {
+		return err
+	}
+	if err := dw.Close(); err != nil {
+		return err
+	}
+
+	_, _, err := text.ReadResponse(250)
+	return err
+}
+
+// smtpErrorCode 提取 SMTP 协议错误携带的响应码，非协议错误返回 0
+func smtpErrorCode(err error) int {
+	if e, ok := err.(*textproto.Error); ok {
+		return e.Code
+	}
+	return 0
+}
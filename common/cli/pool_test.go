@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeSMTPServer listens on an ephemeral port and runs one handler per
+// accepted connection, in order. It stops accepting once all handlers
+// have run or the listener is closed.
+func fakeSMTPServer(t *testing.T, handlers ...func(net.Conn)) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for _, h := range handlers {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			h(conn)
+			conn.Close()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// fakeSMTPSession runs a minimal scripted SMTP/ESMTP dialogue: greeting,
+// EHLO advertising PIPELINING and AUTH PLAIN, a single AUTH round-trip,
+// then MAIL/RCPT/DATA/RSET/QUIT. mailResponse overrides the response line
+// sent for MAIL FROM, letting tests simulate transient failures.
+func fakeSMTPSession(mailResponse string) func(net.Conn) {
+	return func(conn net.Conn) {
+		r := bufio.NewReader(conn)
+		fmt.Fprintf(conn, "220 fake.test ESMTP\r\n")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			upper := strings.ToUpper(line)
+			switch {
+			case strings.HasPrefix(upper, "EHLO"), strings.HasPrefix(upper, "HELO"):
+				fmt.Fprintf(conn, "250-fake.test\r\n250-AUTH PLAIN\r\n250 PIPELINING\r\n")
+			case strings.HasPrefix(upper, "AUTH PLAIN"):
+				fmt.Fprintf(conn, "235 2.7.0 authenticated\r\n")
+			case strings.HasPrefix(upper, "MAIL FROM"):
+				resp := mailResponse
+				if resp == "" {
+					resp = "250 ok"
+				}
+				fmt.Fprintf(conn, "%s\r\n", resp)
+				if strings.HasPrefix(resp, "4") || strings.HasPrefix(resp, "5") {
+					return
+				}
+			case strings.HasPrefix(upper, "RCPT TO"):
+				fmt.Fprintf(conn, "250 ok\r\n")
+			case upper == "DATA":
+				fmt.Fprintf(conn, "354 go ahead\r\n")
+				for {
+					dataLine, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if strings.TrimRight(dataLine, "\r\n") == "." {
+						break
+					}
+				}
+				fmt.Fprintf(conn, "250 queued\r\n")
+			case upper == "RSET":
+				fmt.Fprintf(conn, "250 ok\r\n")
+			case upper == "QUIT":
+				fmt.Fprintf(conn, "221 bye\r\n")
+				return
+			default:
+				fmt.Fprintf(conn, "500 unrecognized\r\n")
+			}
+		}
+	}
+}
+
+func testMessage() *Message {
+	m := NewMessage()
+	m.SetFrom("sender@example.com", "")
+	m.AddTo("to@example.com", "")
+	m.SetSubject("hi")
+	m.SetBody("plain", "hello")
+	return m
+}
+
+func TestPool_Send_Pipelining(t *testing.T) {
+	addr := fakeSMTPServer(t, fakeSMTPSession(""))
+	p := &Pool{Host: addr, User: "u", Passwd: "p", AuthType: AuthPlain}
+
+	if err := p.Send(testMessage()); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+}
+
+func TestPool_Send_RetriesOnTransientError(t *testing.T) {
+	addr := fakeSMTPServer(t,
+		fakeSMTPSession("450 mailbox busy"),
+		fakeSMTPSession(""),
+	)
+	p := &Pool{Host: addr, User: "u", Passwd: "p", AuthType: AuthPlain}
+
+	if err := p.Send(testMessage()); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+}
+
+func TestPool_Send_PermanentErrorStopsRetrying(t *testing.T) {
+	addr := fakeSMTPServer(t, fakeSMTPSession("550 no such user"))
+	p := &Pool{Host: addr, User: "u", Passwd: "p", AuthType: AuthPlain}
+
+	if err := p.Send(testMessage()); err == nil {
+		t.Fatal("Send: want error for permanent 5xx failure, got nil")
+	}
+}
+
+// TestPool_Send_ReusesConnection sends two messages through the same Pool
+// against a single fake connection, exercising the RSET-based reuse path.
+// A stale DATA response left unread by the first Send would desync the
+// session and surface as a spurious error on the second Send.
+func TestPool_Send_ReusesConnection(t *testing.T) {
+	addr := fakeSMTPServer(t, fakeSMTPSession(""))
+	p := &Pool{Host: addr, User: "u", Passwd: "p", AuthType: AuthPlain, Size: 1}
+
+	if err := p.Send(testMessage()); err != nil {
+		t.Fatalf("first Send: %v", err)
+	}
+	if err := p.Send(testMessage()); err != nil {
+		t.Fatalf("second Send: %v", err)
+	}
+}
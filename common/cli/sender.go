@@ -0,0 +1,265 @@
+package cli
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os/exec"
+	"strings"
+)
+
+// Sender 是邮件投递后端的抽象，屏蔽 SMTP/LMTP/本地 MTA 等具体协议差异
+type Sender interface {
+	Send(from string, to []string, msg io.Reader) error
+}
+
+// SMTPSender 通过 STARTTLS 升级明文连接发送，对应历史上 MailSend 的行为
+type SMTPSender struct {
+	Host, User, Passwd string
+	AuthType           AuthType
+	// Token 为 AuthXOAuth2 模式下使用的 OAuth2 access token
+	Token string
+}
+
+// Send 实现 Sender
+func (s *SMTPSender) Send(from string, to []string, msg io.Reader) error {
+	if from == "" || len(to) == 0 {
+		return errors.New("Must specify at least one From address and one To address")
+	}
+	client, err := smtp.Dial(s.Host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	host := strings.Split(s.Host, ":")[0]
+	if err = client.Hello(host); err != nil {
+		return err
+	}
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err = client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+
+	auth, err := chooseAuth(client, host, s.User, s.Passwd, s.Token, s.AuthType)
+	if err != nil {
+		return err
+	}
+	if err = client.Auth(auth); err != nil {
+		return err
+	}
+
+	return deliver(client, from, to, msg)
+}
+
+// SMTPSSender 通过隐式 TLS(通常是 465 端口)连接发送，用于只支持
+// SMTPS 而不支持 STARTTLS 的提交服务器
+type SMTPSSender struct {
+	Host, User, Passwd string
+	AuthType           AuthType
+	Token              string
+}
+
+// Send 实现 Sender
+func (s *SMTPSSender) Send(from string, to []string, msg io.Reader) error {
+	if from == "" || len(to) == 0 {
+		return errors.New("Must specify at least one From address and one To address")
+	}
+	host := strings.Split(s.Host, ":")[0]
+	conn, err := tls.Dial("tcp", s.Host, &tls.Config{ServerName: host})
+	if err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	auth, err := chooseAuth(client, host, s.User, s.Passwd, s.Token, s.AuthType)
+	if err != nil {
+		return err
+	}
+	if err = client.Auth(auth); err != nil {
+		return err
+	}
+
+	return deliver(client, from, to, msg)
+}
+
+// deliver 在已完成 HELO/STARTTLS/AUTH 的 client 上执行 MAIL/RCPT/DATA 并 QUIT，
+// 供 SMTPSender 与 SMTPSSender 共用
+func deliver(client *smtp.Client, from string, to []string, msg io.Reader) error {
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := client.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	if seeker, ok := msg.(io.Seeker); ok {
+		seeker.Seek(0, 0)
+	}
+	if _, err = io.Copy(w, msg); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// LMTPSender 通过 LMTP 协议向本地投递代理(如 Dovecot LMTP)发送邮件。
+// Network 为 "tcp" 或 "unix"，留空时默认为 "tcp"；Addr 为对应的地址或 socket 路径
+type LMTPSender struct {
+	Network string
+	Addr    string
+}
+
+// Send 实现 Sender。与 SMTP 不同，LMTP 在 DATA 结束后按收件人逐条返回投递状态
+func (s *LMTPSender) Send(from string, to []string, msg io.Reader) error {
+	if from == "" || len(to) == 0 {
+		return errors.New("Must specify at least one From address and one To address")
+	}
+	network := s.Network
+	if network == "" {
+		network = "tcp"
+	}
+	conn, err := net.Dial(network, s.Addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	text := textproto.NewConn(conn)
+	if _, _, err = text.ReadResponse(220); err != nil {
+		return err
+	}
+
+	host, _, err := net.SplitHostPort(s.Addr)
+	if err != nil {
+		host = s.Addr
+	}
+	if err = lmtpCmd(text, 250, "LHLO %s", host); err != nil {
+		return err
+	}
+	if err = lmtpCmd(text, 250, "MAIL FROM:<%s>", from); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err = lmtpCmd(text, 250, "RCPT TO:<%s>", addr); err != nil {
+			return err
+		}
+	}
+	if err = lmtpCmd(text, 354, "DATA"); err != nil {
+		return err
+	}
+
+	dw := text.DotWriter()
+	if _, err = io.Copy(dw, msg); err != nil {
+		return err
+	}
+	if err = dw.Close(); err != nil {
+		return err
+	}
+
+	var firstErr error
+	for range to {
+		if _, _, err := text.ReadResponse(250); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+
+	return lmtpCmd(text, 221, "QUIT")
+}
+
+// lmtpCmd 发送一条命令并校验其响应码
+func lmtpCmd(text *textproto.Conn, expectCode int, format string, args ...interface{}) error {
+	id, err := text.Cmd(format, args...)
+	if err != nil {
+		return err
+	}
+	text.StartResponse(id)
+	defer text.EndResponse(id)
+	_, _, err = text.ReadResponse(expectCode)
+	return err
+}
+
+// SendmailSender 把邮件交给本地 sendmail 二进制处理，适合已经配置好本地 MTA 的主机
+type SendmailSender struct {
+	// Path 为 sendmail 可执行文件路径，留空时使用 /usr/sbin/sendmail
+	Path string
+	// Args 为追加的命令行参数，留空时使用 -t -i
+	Args []string
+}
+
+// Send 实现 Sender。-t 让 sendmail 从消息头中的 To/Cc 收件人投递，但消息头不含 Bcc，
+// 所以这里把 to(Message.Recipients() 的 Bcc 并集)都追加为命令行参数，sendmail 会把
+// 它们与头部收件人取并集一起投递，避免 Bcc 收件人被静默漏发
+func (s *SendmailSender) Send(from string, to []string, msg io.Reader) error {
+	if from == "" || len(to) == 0 {
+		return errors.New("Must specify at least one From address and one To address")
+	}
+	path := s.Path
+	if path == "" {
+		path = "/usr/sbin/sendmail"
+	}
+	args := s.Args
+	if args == nil {
+		args = []string{"-t", "-i"}
+	}
+	args = append(append([]string{}, args...), to...)
+
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = msg
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if stderr.Len() > 0 {
+			return fmt.Errorf("sendmail:%s:%s", err, stderr.String())
+		}
+		return err
+	}
+	return nil
+}
+
+// SentMessage 记录一次 MemorySender.Send 调用的内容
+type SentMessage struct {
+	From string
+	To   []string
+	Data []byte
+}
+
+// MemorySender 把邮件缓存在内存中而不经网络发送，供单元测试使用
+type MemorySender struct {
+	Messages []*SentMessage
+}
+
+// Send 实现 Sender
+func (s *MemorySender) Send(from string, to []string, msg io.Reader) error {
+	data, err := io.ReadAll(msg)
+	if err != nil {
+		return err
+	}
+	s.Messages = append(s.Messages, &SentMessage{From: from, To: to, Data: data})
+	return nil
+}
@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeLMTPSession runs a minimal scripted LMTP dialogue: greeting, LHLO,
+// MAIL/RCPT/DATA, then one status response per recipient taken in order
+// from rcptResponses, letting tests simulate a per-recipient delivery
+// failure mixed in with successes.
+func fakeLMTPSession(rcptResponses []string) func(net.Conn) {
+	return func(conn net.Conn) {
+		r := bufio.NewReader(conn)
+		fmt.Fprintf(conn, "220 fake.test LMTP\r\n")
+		nRcpt := 0
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			upper := strings.ToUpper(line)
+			switch {
+			case strings.HasPrefix(upper, "LHLO"):
+				fmt.Fprintf(conn, "250 fake.test\r\n")
+			case strings.HasPrefix(upper, "MAIL FROM"):
+				fmt.Fprintf(conn, "250 ok\r\n")
+			case strings.HasPrefix(upper, "RCPT TO"):
+				nRcpt++
+				fmt.Fprintf(conn, "250 ok\r\n")
+			case upper == "DATA":
+				fmt.Fprintf(conn, "354 go ahead\r\n")
+				for {
+					dataLine, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if strings.TrimRight(dataLine, "\r\n") == "." {
+						break
+					}
+				}
+				for i := 0; i < nRcpt; i++ {
+					resp := "250 delivered"
+					if i < len(rcptResponses) {
+						resp = rcptResponses[i]
+					}
+					fmt.Fprintf(conn, "%s\r\n", resp)
+				}
+			case upper == "QUIT":
+				fmt.Fprintf(conn, "221 bye\r\n")
+				return
+			default:
+				fmt.Fprintf(conn, "500 unrecognized\r\n")
+			}
+		}
+	}
+}
+
+func TestLMTPSender_Send_AllRecipientsAccepted(t *testing.T) {
+	addr := fakeSMTPServer(t, fakeLMTPSession([]string{"250 delivered", "250 delivered"}))
+	s := &LMTPSender{Addr: addr}
+
+	err := s.Send("sender@example.com", []string{"a@example.com", "b@example.com"}, strings.NewReader("hello\r\n"))
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+}
+
+func TestLMTPSender_Send_ReportsPerRecipientFailure(t *testing.T) {
+	addr := fakeSMTPServer(t, fakeLMTPSession([]string{"250 delivered", "550 no such user"}))
+	s := &LMTPSender{Addr: addr}
+
+	err := s.Send("sender@example.com", []string{"a@example.com", "b@example.com"}, strings.NewReader("hello\r\n"))
+	if err == nil {
+		t.Fatal("Send: want error when one recipient is rejected, got nil")
+	}
+}
+
+func TestLMTPSender_Send_RequiresFromAndRecipients(t *testing.T) {
+	s := &LMTPSender{Addr: "127.0.0.1:0"}
+	if err := s.Send("", []string{"a@example.com"}, bytes.NewReader(nil)); err == nil {
+		t.Error("Send: want error for missing From address, got nil")
+	}
+	if err := s.Send("sender@example.com", nil, bytes.NewReader(nil)); err == nil {
+		t.Error("Send: want error for no recipients, got nil")
+	}
+}